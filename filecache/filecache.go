@@ -0,0 +1,98 @@
+// Package filecache tracks which remote assets have already been
+// downloaded, so repeat runs over ISRIC's many-GB catalog can skip
+// unchanged files instead of re-fetching everything. It is deliberately
+// simple: one JSON index file, fsynced on every write so a crash mid-run
+// can't corrupt it.
+package filecache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Entry records what we know about one previously downloaded URL.
+type Entry struct {
+	Path         string `json:"path"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Sha256       string `json:"sha256,omitempty"`
+	Size         int64  `json:"size"`
+}
+
+// Cache is an index of Entry keyed by source URL, persisted as JSON under
+// <targetDir>/.isric-cache/index.json.
+type Cache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]Entry
+}
+
+// Open loads the cache index for targetDir, creating an empty one if it
+// doesn't exist yet.
+func Open(targetDir string) (*Cache, error) {
+	path := filepath.Join(targetDir, ".isric-cache", "index.json")
+
+	c := &Cache{path: path, entries: map[string]Entry{}}
+
+	body, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cache index %s: %v", path, err)
+	}
+	if err := json.Unmarshal(body, &c.entries); err != nil {
+		return nil, fmt.Errorf("unable to parse cache index %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Get returns the entry recorded for url, if any.
+func (c *Cache) Get(url string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	return entry, ok
+}
+
+// Put records entry for url and persists the index.
+func (c *Cache) Put(url string, entry Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = entry
+	return c.save()
+}
+
+// save writes the index to a temp file, fsyncs it, then renames it over
+// the real index path, so a crash never leaves a half-written index.
+func (c *Cache) save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("unable to create cache dir: %v", err)
+	}
+
+	body, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("unable to marshal cache index: %v", err)
+	}
+
+	tmp := c.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("unable to create temp cache index: %v", err)
+	}
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write temp cache index: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to fsync temp cache index: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close temp cache index: %v", err)
+	}
+	return os.Rename(tmp, c.path)
+}
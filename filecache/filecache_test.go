@@ -0,0 +1,54 @@
+package filecache
+
+import "testing"
+
+func TestPutGetRoundTrips(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	if _, ok := c.Get("https://example.com/a.tif"); ok {
+		t.Fatalf("Get on empty cache returned an entry")
+	}
+
+	entry := Entry{Path: "a.tif", ETag: `"abc"`, LastModified: "yesterday", Sha256: "deadbeef", Size: 42}
+	if err := c.Put("https://example.com/a.tif", entry); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok := c.Get("https://example.com/a.tif")
+	if !ok {
+		t.Fatalf("Get after Put: entry not found")
+	}
+	if got != entry {
+		t.Errorf("Get = %+v, want %+v", got, entry)
+	}
+}
+
+func TestOpenReloadsPersistedIndex(t *testing.T) {
+	dir := t.TempDir()
+
+	c1, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	entry := Entry{Path: "b.tif", Size: 7}
+	if err := c1.Put("https://example.com/b.tif", entry); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	c2, err := Open(dir)
+	if err != nil {
+		t.Fatalf("re-Open: %v", err)
+	}
+	got, ok := c2.Get("https://example.com/b.tif")
+	if !ok {
+		t.Fatalf("Get on reopened cache: entry not found")
+	}
+	if got != entry {
+		t.Errorf("Get on reopened cache = %+v, want %+v", got, entry)
+	}
+}
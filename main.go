@@ -1,6 +1,10 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,29 +14,41 @@ import (
 	"os"
 	"os/signal"
 	"path"
-	"regexp"
-	"strings"
+	"path/filepath"
 	"sync"
 	"syscall"
 	"time"
 
 	"gopkg.in/yaml.v3"
-)
 
-type PageParam struct {
-	Name       string   `yaml:"name"`
-	Url        string   `yaml:"url"`
-	PageRanges []string `yaml:"pageRanges"`
-}
+	"github.com/raymanovg/isric/filecache"
+	"github.com/raymanovg/isric/pool"
+	"github.com/raymanovg/isric/source"
+	"github.com/raymanovg/isric/stitch"
+)
 
 type Config struct {
-	TargetDir string      `yaml:"targetDir"`
-	Pages     []PageParam `yaml:"pages"`
+	TargetDir   string          `yaml:"targetDir"`
+	Concurrency int             `yaml:"concurrency"`
+	RateLimit   float64         `yaml:"rateLimit"`
+	MaxAttempts int             `yaml:"maxAttempts"`
+	Pages       []source.Params `yaml:"pages"`
 }
 
-var config = Config{}
+const (
+	defaultConcurrency = 4
+	defaultMaxAttempts = 5
+)
+
+var (
+	config = Config{}
+	cache  *filecache.Cache
+	force  = flag.Bool("force", false, "bypass the on-disk cache and re-download everything")
+)
 
 func main() {
+	flag.Parse()
+
 	yfile, err := ioutil.ReadFile("config.yaml")
 	if err != nil {
 		log.Fatal(err)
@@ -42,175 +58,325 @@ func main() {
 		log.Fatal(err)
 	}
 
-	terminate := make(chan struct{})
+	cache, err = filecache.Open(config.TargetDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	done := make(chan struct{})
 	go func() {
-		run(terminate)
+		run(ctx)
 		close(done)
 	}()
 
 	select {
 	case <-signalCh:
 		fmt.Println("Terminating")
-		close(terminate)
+		cancel()
 		time.Sleep(time.Second * 5)
-		break
 	case <-done:
 		fmt.Println("Done")
 	}
 }
 
-func run(terminate chan struct{}) {
+func run(ctx context.Context) {
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	maxAttempts := config.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	workers := pool.New(concurrency, config.RateLimit, maxAttempts)
+	workers.Start(ctx)
+	go workers.Progress.Report(5 * time.Second)
+
 	wg := sync.WaitGroup{}
 	for _, pageParam := range config.Pages {
 		wg.Add(1)
-		go func(pageParam PageParam) {
-			if err := handle(pageParam, terminate); err != nil {
+		go func(pageParam source.Params) {
+			defer wg.Done()
+			if err := handle(ctx, pageParam, workers); err != nil {
 				fmt.Printf("handling %s failed: %v \n", pageParam.Name, err)
 			}
-			wg.Done()
 		}(pageParam)
 	}
 	wg.Wait()
+
+	workers.Close()
+	workers.Wait()
+	workers.Progress.Stop()
 }
 
-func handle(params PageParam, terminate <-chan struct{}) error {
-	parsedUrl, _ := url.Parse(params.Url)
-	baseURL := *parsedUrl
-	html, err := getHTML(baseURL)
+func handle(ctx context.Context, params source.Params, workers *pool.Pool) error {
+	if params.Stitch && !source.StitchSupported(params.Type) {
+		return fmt.Errorf("stitching is not supported for source type %q (only ISRIC's tileSG-<row><col> tile naming is understood)", params.Type)
+	}
+
+	src, err := source.New(params)
 	if err != nil {
-		return fmt.Errorf("error to get html page: %v", err)
+		return fmt.Errorf("unable to build source for %s: %v", params.Name, err)
 	}
 
-	for pageUrl := range parseURLs(baseURL, html, params.PageRanges) {
-		select {
-		case <-terminate:
-			fmt.Println("handle is terminated")
-			return nil
-		default:
+	assets, err := src.Enumerate(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to enumerate %s: %v", params.Name, err)
+	}
+
+	pageJobs := sync.WaitGroup{}
+	for asset := range assets {
+		asset := asset
+		host := ""
+		if assetUrl, err := url.Parse(asset.URL); err == nil {
+			host = assetUrl.Host
 		}
 
-		html, err := getHTML(pageUrl)
-		if err != nil {
-			log.Printf("error to get html page %s: %v \n", pageUrl.String(), err)
-			continue
+		pageJobs.Add(1)
+		submitted := workers.Submit(ctx, pool.Job{
+			Host: host,
+			Run: func(ctx context.Context) error {
+				n, err := download(ctx, asset)
+				if err == nil {
+					workers.Progress.AddBytes(n)
+				}
+				return err
+			},
+			OnComplete: pageJobs.Done,
+		})
+		if !submitted {
+			pageJobs.Done()
+			break
 		}
-		for fileUrl := range getTifUrls(pageUrl, html) {
-			select {
-			case <-terminate:
-				fmt.Println("downloading is terminated")
-				return nil
-			default:
-			}
-			if _, err := download(fileUrl); err != nil {
-				log.Printf("unable to download file %s: %v", fileUrl.String(), err)
-			}
+	}
+	pageJobs.Wait()
+
+	if params.Stitch {
+		dir := path.Join(config.TargetDir, params.Name)
+		outPath := path.Join(dir, params.Name+".tif")
+		if err := stitch.Dir(dir, outPath, params.COG); err != nil {
+			return fmt.Errorf("unable to stitch %s: %v", params.Name, err)
 		}
 	}
 
 	return nil
 }
 
-func getTifUrls(pageURL url.URL, page []byte) chan url.URL {
-	urlChan := make(chan url.URL)
-	go func() {
-		re := regexp.MustCompile("href=\"(.*\\.tif)\"")
-		matches := re.FindAllStringSubmatch(string(page), -1)
-		for _, m := range matches {
-			tifURL := pageURL
-			tifURL.Path = path.Join(pageURL.Path, m[1])
-			urlChan <- tifURL
+func download(ctx context.Context, asset source.Asset) (int64, error) {
+	fullPath := path.Join(config.TargetDir, asset.RelPath)
+	extra := http.Header{}
+
+	entry, cached := cache.Get(asset.URL)
+	resumable := false
+	if cached && !*force {
+		if info, err := os.Stat(fullPath); err == nil {
+			plan := planResume(entry, info.Size())
+			extra = plan.headers
+			resumable = plan.resumable
 		}
-	}()
+	}
 
-	return urlChan
-}
+	fmt.Printf("Downlading %s \n", asset.URL)
+	assetUrl, err := url.Parse(asset.URL)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse asset url: %v", err)
+	}
 
-func parseURLs(pageURL url.URL, pageBody []byte, pageRanges []string) chan url.URL {
-	urlsCh := make(chan url.URL)
-	stringPageBody := string(pageBody)
-	go func() {
-		for _, tpl := range buildLinkTemplates(pageRanges) {
-			regxStr := fmt.Sprintf("href=\"(%s)\\/\"", tpl)
-			re := regexp.MustCompile(regxStr)
-			matches := re.FindAllStringSubmatch(stringPageBody, -1)
-			for _, m := range matches {
-				link := pageURL
-				link.Path = path.Join(pageURL.Path, m[1]) + "/"
-				urlsCh <- link
-			}
+	response, err := request(ctx, asset.Client, *assetUrl, extra)
+	if err != nil {
+		return 0, fmt.Errorf("unable to request asset %s: %v", asset.URL, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotModified {
+		fmt.Printf("%s is unchanged, skipping \n", asset.URL)
+		return 0, nil
+	}
+	if response.StatusCode >= 400 {
+		return 0, &pool.HTTPError{StatusCode: response.StatusCode}
+	}
+
+	resuming := resumable && response.StatusCode == http.StatusPartialContent
+
+	// Persist the expected final size before we start copying, so a crash
+	// mid-download still leaves behind enough to recognize the partial
+	// file as resumable (rather than stale) on the next run.
+	if expected := expectedSize(response, resuming); expected > 0 {
+		if err := cache.Put(asset.URL, filecache.Entry{
+			Path:         fullPath,
+			ETag:         response.Header.Get("ETag"),
+			LastModified: response.Header.Get("Last-Modified"),
+			Size:         expected,
+		}); err != nil {
+			log.Printf("unable to persist provisional cache entry for %s: %v", asset.URL, err)
 		}
-	}()
+	}
 
-	return urlsCh
-}
+	hasher := sha256.New()
+	if resuming {
+		if err := seedHasher(hasher, fullPath); err != nil {
+			return 0, fmt.Errorf("unable to hash existing partial file: %v", err)
+		}
+	}
 
-func getHTML(url url.URL) ([]byte, error) {
-	response, err := request(url)
+	file, err := openFile(asset.RelPath, resuming)
 	if err != nil {
-		return nil, fmt.Errorf("unable to request page %s: %v", url.String(), err)
+		return 0, fmt.Errorf("unable to downoad file: %v", err)
 	}
-	defer response.Body.Close()
-	return ioutil.ReadAll(response.Body)
-}
+	defer file.Close()
 
-func download(url url.URL) (int64, error) {
-	fmt.Printf("Downlading %s \n", url.String())
-	response, err := request(url)
+	body, err := source.DecodeBody(response)
 	if err != nil {
-		log.Fatalf("unable to request page %s: %s", url.String(), err)
+		return 0, fmt.Errorf("unable to decode asset %s: %v", asset.URL, err)
 	}
-	defer response.Body.Close()
 
-	file, err := createFile(url.Path)
+	n, err := io.Copy(io.MultiWriter(file, hasher), body)
 	if err != nil {
-		return 0, fmt.Errorf("unable to downoad file: %v", err)
+		return n, err
 	}
 
-	defer file.Close()
-	return io.Copy(file, response.Body)
+	info, err := file.Stat()
+	if err != nil {
+		return n, fmt.Errorf("unable to stat downloaded file: %v", err)
+	}
+
+	putErr := cache.Put(asset.URL, filecache.Entry{
+		Path:         fullPath,
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+		Sha256:       hex.EncodeToString(hasher.Sum(nil)),
+		Size:         info.Size(),
+	})
+	if putErr != nil {
+		log.Printf("unable to persist cache entry for %s: %v", asset.URL, putErr)
+	}
+
+	return n, nil
 }
 
-func createFile(urlPath string) (*os.File, error) {
-	parts := strings.Split(urlPath, "/")
-	dir := path.Join(config.TargetDir, strings.Join(parts[len(parts)-3:len(parts)-1], "/"))
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		if err = os.MkdirAll(dir, 0755); err != nil {
-			return nil, fmt.Errorf("unable to create dir %s: %v", dir, err)
+// resumePlan is the outcome of deciding how to (re)request an asset given
+// its cache entry and what's already on disk.
+type resumePlan struct {
+	headers http.Header
+	// resumable is true when headers asks for a byte-range resume, so the
+	// caller knows a 206 response means a genuine partial-file append
+	// rather than a fresh download it should happen to also be partial.
+	resumable bool
+}
+
+// planResume decides how to (re)request asset given entry, its cache
+// entry, and localSize, the size of the file already on disk.
+//
+//   - If entry.Size is unknown (e.g. a chunked response never recorded a
+//     Content-Length), there's nothing to compare the local file against,
+//     so it re-fetches fully rather than guessing at a resume.
+//   - If the local file looks complete (entry.Size known and already
+//     reached), it only asks whether the resource is still fresh
+//     (If-None-Match / If-Modified-Since).
+//   - Otherwise the local file looks like an interrupted download: ask to
+//     resume it with Range + If-Range, never If-None-Match/
+//     If-Modified-Since, which could turn an unchanged-but-partial file
+//     into a false "304 Not Modified" and leave it truncated forever.
+func planResume(entry filecache.Entry, localSize int64) resumePlan {
+	headers := http.Header{}
+
+	switch {
+	case entry.Size <= 0:
+		return resumePlan{headers: headers}
+	case localSize >= entry.Size:
+		if entry.ETag != "" {
+			headers.Set("If-None-Match", entry.ETag)
 		}
+		if entry.LastModified != "" {
+			headers.Set("If-Modified-Since", entry.LastModified)
+		}
+		return resumePlan{headers: headers}
+	case localSize > 0:
+		headers.Set("Range", fmt.Sprintf("bytes=%d-", localSize))
+		if entry.ETag != "" {
+			headers.Set("If-Range", entry.ETag)
+		} else if entry.LastModified != "" {
+			headers.Set("If-Range", entry.LastModified)
+		}
+		return resumePlan{headers: headers, resumable: true}
+	default:
+		return resumePlan{headers: headers}
 	}
-	return os.Create(path.Join(dir, parts[len(parts)-1]))
 }
 
-func buildLinkTemplates(ranges []string) []string {
-	templates := make([]string, 0, len(ranges))
-	for _, p := range ranges {
-		tplBuilder := strings.Builder{}
-		for _, part := range strings.Split(p, "|") {
-			if tplBuilder.Len() > 0 {
-				tplBuilder.WriteString("|")
-			}
-			tplBuilder.WriteString("tileSG-")
-			tplBuilder.WriteString(part)
+// expectedSize derives the final on-disk size of the asset being
+// downloaded: the declared total for a 206 partial response (from
+// Content-Range), or the response's own length otherwise.
+func expectedSize(response *http.Response, resuming bool) int64 {
+	if resuming {
+		_, total, ok := parseContentRange(response.Header.Get("Content-Range"))
+		if ok {
+			return total
 		}
-		templates = append(templates, tplBuilder.String())
+		return 0
 	}
-	return templates
+	return response.ContentLength
 }
 
-func request(url url.URL) (*http.Response, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// parseContentRange extracts the served range start and the total resource
+// size from a "Content-Range: bytes start-end/total" header.
+func parseContentRange(header string) (start, total int64, ok bool) {
+	var end int64
+	n, err := fmt.Sscanf(header, "bytes %d-%d/%d", &start, &end, &total)
+	if err != nil || n != 3 {
+		return 0, 0, false
 	}
-	request, err := http.NewRequest("GET", url.String(), nil)
+	return start, total, true
+}
+
+// seedHasher feeds the bytes already on disk at path into hasher, so a
+// resumed download's recorded checksum covers the whole file rather than
+// just the newly-appended tail.
+func seedHasher(hasher io.Writer, path string) error {
+	existing, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer existing.Close()
+
+	_, err = io.Copy(hasher, existing)
+	return err
+}
+
+func openFile(relPath string, appendTo bool) (*os.File, error) {
+	fullPath := path.Join(config.TargetDir, relPath)
+	dir := filepath.Dir(fullPath)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("unable to create dir %s: %v", dir, err)
+		}
+	}
+	if appendTo {
+		return os.OpenFile(fullPath, os.O_APPEND|os.O_WRONLY, 0644)
+	}
+	return os.Create(fullPath)
+}
+
+func request(ctx context.Context, client *http.Client, url url.URL, extra http.Header) (*http.Response, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	request, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("unable create request: %v", err)
 	}
 	request.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/95.0.4638.54 Safari/537.36")
 	request.Header.Set("accept-language", "en-GB,en-US;q=0.9,en;q=0.8,ru;q=0.7,kk;q=0.6")
 	request.Header.Set("accept-encoding", "gzip, deflate, br")
+	for key, values := range extra {
+		for _, v := range values {
+			request.Header.Set(key, v)
+		}
+	}
 	return client.Do(request)
 }
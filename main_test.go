@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/raymanovg/isric/filecache"
+)
+
+func TestPlanResumeUnknownSize(t *testing.T) {
+	plan := planResume(filecache.Entry{ETag: `"abc"`}, 100)
+	if plan.resumable {
+		t.Errorf("resumable = true, want false when entry.Size is unknown")
+	}
+	if len(plan.headers) != 0 {
+		t.Errorf("headers = %v, want empty", plan.headers)
+	}
+}
+
+func TestPlanResumeCompleteFileChecksFreshness(t *testing.T) {
+	entry := filecache.Entry{Size: 100, ETag: `"abc"`, LastModified: "yesterday"}
+	plan := planResume(entry, 100)
+	if plan.resumable {
+		t.Errorf("resumable = true, want false for a complete file")
+	}
+	if got := plan.headers.Get("If-None-Match"); got != `"abc"` {
+		t.Errorf("If-None-Match = %q, want %q", got, `"abc"`)
+	}
+	if got := plan.headers.Get("If-Modified-Since"); got != "yesterday" {
+		t.Errorf("If-Modified-Since = %q, want %q", got, "yesterday")
+	}
+	if plan.headers.Get("Range") != "" {
+		t.Errorf("Range = %q, want empty for a complete file", plan.headers.Get("Range"))
+	}
+}
+
+func TestPlanResumePartialFileResumesWithETag(t *testing.T) {
+	entry := filecache.Entry{Size: 100, ETag: `"abc"`, LastModified: "yesterday"}
+	plan := planResume(entry, 40)
+	if !plan.resumable {
+		t.Errorf("resumable = false, want true for a partial file")
+	}
+	if got := plan.headers.Get("Range"); got != "bytes=40-" {
+		t.Errorf("Range = %q, want %q", got, "bytes=40-")
+	}
+	if got := plan.headers.Get("If-Range"); got != `"abc"` {
+		t.Errorf("If-Range = %q, want %q (should prefer ETag)", got, `"abc"`)
+	}
+	if plan.headers.Get("If-None-Match") != "" {
+		t.Errorf("If-None-Match = %q, want empty for a resume", plan.headers.Get("If-None-Match"))
+	}
+}
+
+func TestPlanResumePartialFileResumesWithLastModifiedOnly(t *testing.T) {
+	entry := filecache.Entry{Size: 100, LastModified: "yesterday"}
+	plan := planResume(entry, 40)
+	if !plan.resumable {
+		t.Errorf("resumable = false, want true for a partial file")
+	}
+	if got := plan.headers.Get("If-Range"); got != "yesterday" {
+		t.Errorf("If-Range = %q, want %q (should fall back to Last-Modified)", got, "yesterday")
+	}
+}
+
+func TestPlanResumeEmptyLocalFile(t *testing.T) {
+	entry := filecache.Entry{Size: 100, ETag: `"abc"`}
+	plan := planResume(entry, 0)
+	if plan.resumable {
+		t.Errorf("resumable = true, want false for an empty local file")
+	}
+	if len(plan.headers) != 0 {
+		t.Errorf("headers = %v, want empty for an empty local file", plan.headers)
+	}
+}
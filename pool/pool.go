@@ -0,0 +1,197 @@
+// Package pool runs download jobs over a bounded set of workers, sharing a
+// per-host rate limit and retrying transient failures with backoff, so one
+// slow or flaky server can no longer block every other download behind it.
+package pool
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// HTTPError lets a Job report the response status it got, so the pool can
+// decide whether the failure is worth retrying.
+type HTTPError struct {
+	StatusCode int
+}
+
+func (e *HTTPError) Error() string {
+	return "unexpected http status " + strconv.Itoa(e.StatusCode)
+}
+
+// Job is one unit of work submitted to the pool.
+type Job struct {
+	// Host scopes the rate limiter; jobs for the same host share a
+	// budget, jobs for different hosts don't compete with each other.
+	Host string
+	Run  func(ctx context.Context) error
+	// OnComplete, if set, runs exactly once after the job either
+	// succeeds or exhausts its retries - never once per attempt. Callers
+	// use it to track when a batch of submitted jobs has fully drained.
+	OnComplete func()
+}
+
+// Pool is a bounded worker pool with shared per-host rate limiting and
+// per-job retry.
+type Pool struct {
+	jobs        chan Job
+	workers     int
+	maxAttempts int
+
+	limitersMu sync.Mutex
+	limiters   map[string]*rate.Limiter
+	ratePerSec float64
+
+	wg sync.WaitGroup
+
+	Progress *Progress
+}
+
+// New builds a Pool with workers concurrent goroutines, a ratePerSec
+// requests/sec budget per host (0 disables limiting), and up to
+// maxAttempts tries per job.
+func New(workers int, ratePerSec float64, maxAttempts int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Pool{
+		jobs:        make(chan Job),
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		limiters:    map[string]*rate.Limiter{},
+		ratePerSec:  ratePerSec,
+		Progress:    newProgress(),
+	}
+}
+
+// Start launches the worker goroutines. Call Submit to feed them and
+// Close+Wait to drain.
+func (p *Pool) Start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.worker(ctx)
+	}
+}
+
+// Submit enqueues job, blocking until a worker is free or ctx is done. It
+// reports whether the job was actually enqueued; on false (ctx cancelled)
+// job never ran and its OnComplete was not called.
+func (p *Pool) Submit(ctx context.Context, job Job) bool {
+	select {
+	case p.jobs <- job:
+		p.Progress.submitted()
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Close signals that no more jobs will be submitted.
+func (p *Pool) Close() {
+	close(p.jobs)
+}
+
+// Wait blocks until every worker has drained the job channel.
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+func (p *Pool) worker(ctx context.Context) {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		p.runWithRetry(ctx, job)
+	}
+}
+
+func (p *Pool) runWithRetry(ctx context.Context, job Job) {
+	p.Progress.start()
+	defer p.Progress.finish()
+	if job.OnComplete != nil {
+		defer job.OnComplete()
+	}
+
+	limiter := p.limiterFor(job.Host)
+
+	var err error
+	for attempt := 1; attempt <= p.maxAttempts; attempt++ {
+		if limiter != nil {
+			if err = limiter.Wait(ctx); err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		err = job.Run(ctx)
+		if err == nil {
+			return
+		}
+		if !Retryable(err) || attempt == p.maxAttempts {
+			return
+		}
+
+		backoff := time.Duration(attempt*attempt) * 250 * time.Millisecond
+		jitter := time.Duration(rand.Int63n(int64(250 * time.Millisecond)))
+		select {
+		case <-time.After(backoff + jitter):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Pool) limiterFor(host string) *rate.Limiter {
+	if p.ratePerSec <= 0 {
+		return nil
+	}
+
+	p.limitersMu.Lock()
+	defer p.limitersMu.Unlock()
+	limiter, ok := p.limiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(p.ratePerSec), 1)
+		p.limiters[host] = limiter
+	}
+	return limiter
+}
+
+// Retryable reports whether err looks like a transient failure worth
+// retrying: a 5xx response, a network timeout, or a connection reset.
+func Retryable(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	// Checked ahead of the generic net.Error case below: net.ErrClosed
+	// satisfies net.Error with Timeout() == false, so it would otherwise
+	// be shadowed and wrongly treated as non-retryable.
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+
+	return false
+}
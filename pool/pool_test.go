@@ -0,0 +1,70 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestRetryableHTTPError(t *testing.T) {
+	cases := []struct {
+		status int
+		want   bool
+	}{
+		{500, true},
+		{503, true},
+		{404, false},
+		{400, false},
+	}
+	for _, c := range cases {
+		err := &HTTPError{StatusCode: c.status}
+		if got := Retryable(err); got != c.want {
+			t.Errorf("Retryable(HTTPError{%d}) = %v, want %v", c.status, got, c.want)
+		}
+	}
+}
+
+func TestRetryableConnectionErrors(t *testing.T) {
+	cases := []error{
+		&net.OpError{Op: "read", Err: syscall.ECONNRESET},
+		&net.OpError{Op: "write", Err: syscall.ECONNABORTED},
+		&net.OpError{Op: "write", Err: syscall.EPIPE},
+		context.DeadlineExceeded,
+		net.ErrClosed,
+	}
+	for _, err := range cases {
+		if !Retryable(err) {
+			t.Errorf("Retryable(%v) = false, want true", err)
+		}
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestRetryableNetTimeout(t *testing.T) {
+	var err net.Error = timeoutError{}
+	if !Retryable(err) {
+		t.Errorf("Retryable(timeout net.Error) = false, want true")
+	}
+}
+
+func TestRetryableUnrelatedError(t *testing.T) {
+	err := errors.New("something else went wrong")
+	if Retryable(err) {
+		t.Errorf("Retryable(%v) = true, want false", err)
+	}
+}
+
+func TestRetryableWrappedError(t *testing.T) {
+	err := fmt.Errorf("request failed: %w", &HTTPError{StatusCode: 502})
+	if !Retryable(err) {
+		t.Errorf("Retryable(wrapped 502) = false, want true")
+	}
+}
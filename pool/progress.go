@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Progress tracks coarse counters for the pool's Report to print, since
+// individual jobs only know about their own transfer, not the run as a
+// whole.
+type Progress struct {
+	submittedCount int64
+	finishedCount  int64
+	inFlightCount  int64
+	bytes          int64
+
+	startedAt time.Time
+	stop      chan struct{}
+}
+
+func newProgress() *Progress {
+	return &Progress{startedAt: time.Time{}, stop: make(chan struct{})}
+}
+
+func (p *Progress) submitted() { atomic.AddInt64(&p.submittedCount, 1) }
+
+func (p *Progress) start() { atomic.AddInt64(&p.inFlightCount, 1) }
+
+func (p *Progress) finish() {
+	atomic.AddInt64(&p.inFlightCount, -1)
+	atomic.AddInt64(&p.finishedCount, 1)
+}
+
+// AddBytes records n more bytes downloaded, for the downloaded total that
+// Report prints. The ETA is derived from job counts, not from bytes.
+func (p *Progress) AddBytes(n int64) {
+	atomic.AddInt64(&p.bytes, n)
+}
+
+// Report prints progress to stderr every interval until stop is called.
+func (p *Progress) Report(interval time.Duration) {
+	p.startedAt = time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.print()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *Progress) print() {
+	submitted := atomic.LoadInt64(&p.submittedCount)
+	finished := atomic.LoadInt64(&p.finishedCount)
+	inFlight := atomic.LoadInt64(&p.inFlightCount)
+	bytes := atomic.LoadInt64(&p.bytes)
+
+	elapsed := time.Since(p.startedAt)
+	eta := "unknown"
+	if finished > 0 && submitted > finished {
+		perJob := elapsed / time.Duration(finished)
+		eta = (perJob * time.Duration(submitted-finished)).Round(time.Second).String()
+	}
+
+	fmt.Fprintf(os.Stderr, "progress: %d/%d done, %d in-flight, %.1f MB downloaded, eta %s\n",
+		finished, submitted, inFlight, float64(bytes)/(1<<20), eta)
+}
+
+// Stop ends the Report loop.
+func (p *Progress) Stop() {
+	close(p.stop)
+}
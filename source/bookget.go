@@ -0,0 +1,89 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path"
+)
+
+func init() {
+	Register("bookget", newBookget)
+}
+
+// bookgetPage is one entry of a bookget-style manifest: a JSON list of the
+// pages that make up a digitised book or map, as produced by several
+// library digitisation portals.
+type bookgetPage struct {
+	Pid    string `json:"pid"`
+	Url    string `json:"url"`
+	Label  string `json:"label"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// bookgetSource first fetches a JSON manifest of pages, then downloads each
+// page's image. Requests share a cookie jar, since these portals commonly
+// gate image downloads behind a session cookie obtained while fetching the
+// manifest.
+type bookgetSource struct {
+	params Params
+}
+
+func newBookget(params Params) (Source, error) {
+	return &bookgetSource{params: params}, nil
+}
+
+func (s *bookgetSource) Enumerate(ctx context.Context) (<-chan Asset, error) {
+	manifestURL := s.params.Manifest
+	if manifestURL == "" {
+		manifestURL = s.params.Url
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cookie jar: %v", err)
+	}
+	client := &http.Client{Jar: jar}
+
+	resp, err := client.Get(manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch manifest %s: %v", manifestURL, err)
+	}
+	defer resp.Body.Close()
+
+	var pages []bookgetPage
+	if err := json.NewDecoder(resp.Body).Decode(&pages); err != nil {
+		return nil, fmt.Errorf("unable to parse manifest %s: %v", manifestURL, err)
+	}
+
+	assets := make(chan Asset)
+	go func() {
+		defer close(assets)
+		for _, page := range pages {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			pageURL, err := url.Parse(page.Url)
+			if err != nil {
+				continue
+			}
+
+			fileName := page.Pid + path.Ext(pageURL.Path)
+
+			select {
+			case <-ctx.Done():
+				return
+			case assets <- Asset{URL: pageURL.String(), RelPath: path.Join(s.params.Name, fileName), Client: client}:
+			}
+		}
+	}()
+
+	return assets, nil
+}
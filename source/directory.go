@@ -0,0 +1,107 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	Register("directory-listing", newDirectoryListing)
+}
+
+// directoryListingSource walks a generic Apache/nginx autoindex page,
+// recursing into sub-directory links and collecting files matching
+// PageRanges' extension. Unlike isricSource it doesn't assume ISRIC's
+// tileSG-<row><col> naming.
+type directoryListingSource struct {
+	params Params
+}
+
+func newDirectoryListing(params Params) (Source, error) {
+	return &directoryListingSource{params: params}, nil
+}
+
+var directoryLinkRe = regexp.MustCompile(`href="([^"?/][^"]*)"`)
+
+func (s *directoryListingSource) Enumerate(ctx context.Context) (<-chan Asset, error) {
+	parsedUrl, err := url.Parse(s.params.Url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse url: %v", err)
+	}
+	baseURL := *parsedUrl
+
+	assets := make(chan Asset)
+	go func() {
+		defer close(assets)
+		s.walk(ctx, baseURL, map[string]bool{}, assets)
+	}()
+
+	return assets, nil
+}
+
+// walk recurses into dirURL's sub-directory links, tracking every
+// directory path already visited in visited so that an autoindex's
+// "../" parent link (or any other cycle back up the tree) doesn't send
+// it into unbounded recursion.
+func (s *directoryListingSource) walk(ctx context.Context, dirURL url.URL, visited map[string]bool, assets chan<- Asset) {
+	if visited[dirURL.Path] {
+		return
+	}
+	visited[dirURL.Path] = true
+
+	html, err := getHTML(dirURL)
+	if err != nil {
+		return
+	}
+
+	for _, m := range directoryLinkRe.FindAllStringSubmatch(string(html), -1) {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		href := m[1]
+		if href == "." || href == ".." || href == "./" || href == "../" {
+			continue
+		}
+
+		linkURL := dirURL
+		linkURL.Path = path.Join(dirURL.Path, href)
+
+		if strings.HasSuffix(href, "/") {
+			s.walk(ctx, linkURL, visited, assets)
+			continue
+		}
+
+		if !s.matches(href) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case assets <- Asset{URL: linkURL.String(), RelPath: strings.TrimPrefix(linkURL.Path, "/")}:
+		}
+	}
+}
+
+// matches reports whether href should be downloaded. PageRanges doubles as
+// a list of file extensions here (e.g. "tif", "jpg"); an empty list keeps
+// today's ISRIC default of .tif only.
+func (s *directoryListingSource) matches(href string) bool {
+	ranges := s.params.PageRanges
+	if len(ranges) == 0 {
+		ranges = []string{"tif"}
+	}
+	for _, ext := range ranges {
+		if strings.HasSuffix(href, "."+ext) {
+			return true
+		}
+	}
+	return false
+}
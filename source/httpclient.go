@@ -0,0 +1,57 @@
+package source
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func request(u url.URL) (*http.Response, error) {
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable create request: %v", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/95.0.4638.54 Safari/537.36")
+	req.Header.Set("accept-language", "en-GB,en-US;q=0.9,en;q=0.8,ru;q=0.7,kk;q=0.6")
+	req.Header.Set("accept-encoding", "gzip, deflate, br")
+	return client.Do(req)
+}
+
+func getHTML(u url.URL) ([]byte, error) {
+	response, err := request(u)
+	if err != nil {
+		return nil, fmt.Errorf("unable to request page %s: %v", u.String(), err)
+	}
+	defer response.Body.Close()
+
+	body, err := DecodeBody(response)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode page %s: %v", u.String(), err)
+	}
+	return ioutil.ReadAll(body)
+}
+
+// DecodeBody wraps response.Body according to its Content-Encoding, since
+// every request we make advertises accept-encoding: gzip, deflate, br -
+// callers fetching anything, HTML or binary assets, need to decode through
+// this before reading. Only gzip and br are decoded; a body in any other
+// encoding, deflate included, is returned as is.
+func DecodeBody(response *http.Response) (io.Reader, error) {
+	switch response.Header.Get("Content-Encoding") {
+	case "gzip":
+		return gzip.NewReader(response.Body)
+	case "br":
+		return brotli.NewReader(response.Body), nil
+	default:
+		return response.Body, nil
+	}
+}
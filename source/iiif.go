@@ -0,0 +1,143 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	Register("iiif", newIIIF)
+}
+
+// iiifSource enumerates the tiles of a IIIF Image API v2/v3 service
+// described by a single info.json document.
+type iiifSource struct {
+	params Params
+}
+
+func newIIIF(params Params) (Source, error) {
+	return &iiifSource{params: params}, nil
+}
+
+// iiifInfo is the subset of an IIIF Image API info.json we need to tile an
+// image. It covers both the v2 ("@id"/"@context") and v3 ("id"/"context")
+// shapes.
+type iiifInfo struct {
+	ContextV2 string `json:"@context"`
+	ContextV3 string `json:"context"`
+	IdV2      string `json:"@id"`
+	IdV3      string `json:"id"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Tiles     []struct {
+		Width        int   `json:"width"`
+		Height       int   `json:"height"`
+		ScaleFactors []int `json:"scaleFactors"`
+	} `json:"tiles"`
+}
+
+func (info iiifInfo) id() string {
+	if info.IdV3 != "" {
+		return info.IdV3
+	}
+	return info.IdV2
+}
+
+// isV3 reports whether info.json describes a IIIF Image API v3 service,
+// which uses "max" instead of "full" as the full-region/full-size keyword.
+func (info iiifInfo) isV3() bool {
+	return strings.Contains(info.ContextV3, "/3/") || strings.Contains(info.ContextV2, "/3/")
+}
+
+func (s *iiifSource) Enumerate(ctx context.Context) (<-chan Asset, error) {
+	infoURL, err := url.Parse(s.params.Url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse info.json url: %v", err)
+	}
+
+	body, err := getHTML(*infoURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch iiif info.json: %v", err)
+	}
+
+	var info iiifInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("unable to parse iiif info.json: %v", err)
+	}
+
+	tileW, tileH := s.params.TileWidth, s.params.TileHeight
+	scaleFactors := []int{1}
+	if len(info.Tiles) > 0 {
+		tileW, tileH = info.Tiles[0].Width, info.Tiles[0].Height
+		scaleFactors = info.Tiles[0].ScaleFactors
+	}
+	if tileW == 0 {
+		return nil, fmt.Errorf("no tile size declared in info.json and no tileWidth configured")
+	}
+	if tileH == 0 {
+		tileH = tileW
+	}
+
+	fullKeyword := "full"
+	if info.isV3() {
+		fullKeyword = "max"
+	}
+
+	assets := make(chan Asset)
+	go func() {
+		defer close(assets)
+		for _, scaleFactor := range scaleFactors {
+			cols := int(math.Ceil(float64(info.Width) / float64(tileW*scaleFactor)))
+			rows := int(math.Ceil(float64(info.Height) / float64(tileH*scaleFactor)))
+
+			for y := 0; y < rows; y++ {
+				for x := 0; x < cols; x++ {
+					asset, err := tileAsset(info, fullKeyword, s.params.Name, tileW, tileH, x, y, scaleFactor)
+					if err != nil {
+						continue
+					}
+
+					select {
+					case <-ctx.Done():
+						return
+					case assets <- asset:
+					}
+				}
+			}
+		}
+	}()
+
+	return assets, nil
+}
+
+func tileAsset(info iiifInfo, fullKeyword, name string, tileW, tileH, x, y, scaleFactor int) (Asset, error) {
+	regionX := x * tileW * scaleFactor
+	regionY := y * tileH * scaleFactor
+	regionW := min(tileW*scaleFactor, info.Width-regionX)
+	regionH := min(tileH*scaleFactor, info.Height-regionY)
+
+	region := fmt.Sprintf("%d,%d,%d,%d", regionX, regionY, regionW, regionH)
+	if regionX == 0 && regionY == 0 && regionW == info.Width && regionH == info.Height {
+		region = fullKeyword
+	}
+	size := fmt.Sprintf("%d,", int(math.Ceil(float64(regionW)/float64(scaleFactor))))
+
+	tileURL := fmt.Sprintf("%s/%s/%s/0/default.jpg", info.id(), region, size)
+	if _, err := url.Parse(tileURL); err != nil {
+		return Asset{}, fmt.Errorf("unable to build iiif tile url: %v", err)
+	}
+
+	fileName := fmt.Sprintf("tile-%d-%d-s%d.jpg", x, y, scaleFactor)
+	return Asset{URL: tileURL, RelPath: name + "/" + fileName}, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
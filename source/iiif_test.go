@@ -0,0 +1,57 @@
+package source
+
+import "testing"
+
+func TestTileAssetFullImageUsesFullKeyword(t *testing.T) {
+	info := iiifInfo{IdV2: "https://example.com/iiif/img1", Width: 100, Height: 100}
+	asset, err := tileAsset(info, "full", "page", 512, 512, 0, 0, 1)
+	if err != nil {
+		t.Fatalf("tileAsset: %v", err)
+	}
+	want := "https://example.com/iiif/img1/full/100,/0/default.jpg"
+	if asset.URL != want {
+		t.Errorf("URL = %q, want %q", asset.URL, want)
+	}
+	if asset.RelPath != "page/tile-0-0-s1.jpg" {
+		t.Errorf("RelPath = %q, want %q", asset.RelPath, "page/tile-0-0-s1.jpg")
+	}
+}
+
+func TestTileAssetEdgeTileIsClamped(t *testing.T) {
+	info := iiifInfo{IdV2: "https://example.com/iiif/img1", Width: 1000, Height: 1000}
+	asset, err := tileAsset(info, "full", "page", 512, 512, 1, 1, 1)
+	if err != nil {
+		t.Fatalf("tileAsset: %v", err)
+	}
+	want := "https://example.com/iiif/img1/512,512,488,488/488,/0/default.jpg"
+	if asset.URL != want {
+		t.Errorf("URL = %q, want %q (edge tile region/size should clamp to image bounds)", asset.URL, want)
+	}
+}
+
+func TestTileAssetScalesSizeByScaleFactor(t *testing.T) {
+	info := iiifInfo{IdV2: "https://example.com/iiif/img1", Width: 2048, Height: 2048}
+	asset, err := tileAsset(info, "full", "page", 512, 512, 0, 0, 2)
+	if err != nil {
+		t.Fatalf("tileAsset: %v", err)
+	}
+	want := "https://example.com/iiif/img1/0,0,1024,1024/512,/0/default.jpg"
+	if asset.URL != want {
+		t.Errorf("URL = %q, want %q", asset.URL, want)
+	}
+	if asset.RelPath != "page/tile-0-0-s2.jpg" {
+		t.Errorf("RelPath = %q, want %q", asset.RelPath, "page/tile-0-0-s2.jpg")
+	}
+}
+
+func TestIsV3DetectsContextVersion(t *testing.T) {
+	v2 := iiifInfo{ContextV2: "http://iiif.io/api/image/2/context.json"}
+	if v2.isV3() {
+		t.Errorf("isV3() = true for a v2 context, want false")
+	}
+
+	v3 := iiifInfo{ContextV3: "http://iiif.io/api/image/3/context.json"}
+	if !v3.isV3() {
+		t.Errorf("isV3() = false for a v3 context, want true")
+	}
+}
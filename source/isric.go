@@ -0,0 +1,192 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+func init() {
+	Register("isric", newISRIC)
+}
+
+// isricSource scrapes ISRIC's directory-listing HTML: a page of sub-page
+// links filtered by PageRanges, each of which in turn lists .tif assets.
+// Which elements count as "links" and "assets" is driven by CSS selectors
+// so the scraper survives ISRIC reshuffling its markup; both default to
+// matching today's behaviour.
+type isricSource struct {
+	params Params
+}
+
+func newISRIC(params Params) (Source, error) {
+	return &isricSource{params: params}, nil
+}
+
+const (
+	defaultLinkSelector  = "a[href]"
+	defaultAssetSelector = "a[href]"
+	defaultAssetPattern  = `\.tif$`
+)
+
+func (s *isricSource) Enumerate(ctx context.Context) (<-chan Asset, error) {
+	parsedUrl, err := url.Parse(s.params.Url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse url: %v", err)
+	}
+	baseURL := *parsedUrl
+
+	body, err := getHTML(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("error to get html page: %v", err)
+	}
+
+	assets := make(chan Asset)
+	go func() {
+		defer close(assets)
+		for pageUrl := range s.links(baseURL, body) {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			pageBody, err := getHTML(pageUrl)
+			if err != nil {
+				continue
+			}
+			for fileUrl := range s.assets(pageUrl, pageBody) {
+				select {
+				case <-ctx.Done():
+					return
+				case assets <- Asset{URL: fileUrl.String(), RelPath: s.relPath(fileUrl.Path)}:
+				}
+			}
+		}
+	}()
+
+	return assets, nil
+}
+
+// relPath groups every tile belonging to a page under one directory named
+// after it, so a later stitching step can find them all in one place.
+func (s *isricSource) relPath(urlPath string) string {
+	return path.Join(s.params.Name, path.Base(urlPath))
+}
+
+// links selects the sub-page directory links restricted to PageRanges
+// (e.g. "tileSG-00/", "tileSG-01/", ...).
+func (s *isricSource) links(pageURL url.URL, body []byte) chan url.URL {
+	selector := s.params.LinkSelector
+	if selector == "" {
+		selector = defaultLinkSelector
+	}
+
+	templates := buildLinkTemplates(s.params.PageRanges)
+	pattern := regexp.MustCompile(fmt.Sprintf(`^(?:%s)/$`, strings.Join(templates, "|")))
+
+	return selectURLs(pageURL, body, selector, pattern, true)
+}
+
+// assets selects the downloadable .tif links (or whatever urlPattern
+// overrides that to) on one sub-page.
+func (s *isricSource) assets(pageURL url.URL, body []byte) chan url.URL {
+	selector := s.params.AssetSelector
+	if selector == "" {
+		selector = defaultAssetSelector
+	}
+
+	patternStr := s.params.URLPattern
+	if patternStr == "" {
+		patternStr = defaultAssetPattern
+	}
+	pattern := regexp.MustCompile(patternStr)
+
+	return selectURLs(pageURL, body, selector, pattern, false)
+}
+
+// selectURLs parses body as HTML, runs selector over it with cascadia, and
+// resolves every matched href against pageURL, keeping only those matching
+// pattern. When appendSlash is true, "/" is appended to the resolved path
+// (used for directory links: path.Join drops the trailing slash their href
+// carries, and the directory listing itself uses it).
+func selectURLs(pageURL url.URL, body []byte, selector string, pattern *regexp.Regexp, appendSlash bool) chan url.URL {
+	urlChan := make(chan url.URL)
+	go func() {
+		defer close(urlChan)
+
+		sel, err := cascadia.Parse(selector)
+		if err != nil {
+			return
+		}
+		doc, err := html.Parse(bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+
+		for _, node := range cascadia.QueryAll(doc, sel) {
+			href := attr(node, "href")
+			if href == "" || !pattern.MatchString(href) {
+				continue
+			}
+
+			link := pageURL
+			link.Path = path.Join(pageURL.Path, href)
+			if appendSlash {
+				link.Path += "/"
+			}
+			urlChan <- link
+		}
+	}()
+
+	return urlChan
+}
+
+func attr(node *html.Node, name string) string {
+	for _, a := range node.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func buildLinkTemplates(ranges []string) []string {
+	templates := make([]string, 0, len(ranges))
+	for _, p := range ranges {
+		tplBuilder := strings.Builder{}
+		for _, part := range strings.Split(p, "|") {
+			if tplBuilder.Len() > 0 {
+				tplBuilder.WriteString("|")
+			}
+			tplBuilder.WriteString("tileSG-")
+			tplBuilder.WriteString(part)
+		}
+		templates = append(templates, tplBuilder.String())
+	}
+	return templates
+}
+
+var tileCoordRe = regexp.MustCompile(`tileSG-(\d{2})(\d{2})\.tif$`)
+
+// ParseTileCoord recovers the row/col grid indices encoded in an ISRIC tile
+// file name of the form "tileSG-<row><col>.tif" (e.g. "tileSG-0003.tif" is
+// row 0, col 3), so downloaded tiles can later be reassembled into a
+// mosaic.
+func ParseTileCoord(fileName string) (row, col int, ok bool) {
+	m := tileCoordRe.FindStringSubmatch(fileName)
+	if m == nil {
+		return 0, 0, false
+	}
+	row, _ = strconv.Atoi(m[1])
+	col, _ = strconv.Atoi(m[2])
+	return row, col, true
+}
@@ -0,0 +1,44 @@
+package source
+
+import "testing"
+
+func TestParseTileCoord(t *testing.T) {
+	cases := []struct {
+		name        string
+		wantRow     int
+		wantCol     int
+		wantMatched bool
+	}{
+		{"tileSG-0003.tif", 0, 3, true},
+		{"tileSG-1204.tif", 12, 4, true},
+		{"tileSG-00.tif", 0, 0, false},
+		{"tile-0-3-s1.jpg", 0, 0, false},
+		{"tileSG-0003.jpg", 0, 0, false},
+	}
+	for _, c := range cases {
+		row, col, ok := ParseTileCoord(c.name)
+		if ok != c.wantMatched {
+			t.Errorf("ParseTileCoord(%q) ok = %v, want %v", c.name, ok, c.wantMatched)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if row != c.wantRow || col != c.wantCol {
+			t.Errorf("ParseTileCoord(%q) = (%d, %d), want (%d, %d)", c.name, row, col, c.wantRow, c.wantCol)
+		}
+	}
+}
+
+func TestBuildLinkTemplates(t *testing.T) {
+	got := buildLinkTemplates([]string{"00|01", "02"})
+	want := []string{"tileSG-00|tileSG-01", "tileSG-02"}
+	if len(got) != len(want) {
+		t.Fatalf("buildLinkTemplates = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("buildLinkTemplates[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
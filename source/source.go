@@ -0,0 +1,100 @@
+// Package source enumerates the downloadable assets exposed by a configured
+// page, independently of where or how that page publishes them. Concrete
+// backends (ISRIC's directory scraping, IIIF, generic autoindexes, ...)
+// register themselves under a type name and are selected through
+// Params.Type.
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Asset is a single file a Source wants downloaded.
+type Asset struct {
+	// URL is the absolute location to fetch.
+	URL string
+	// RelPath is the file's path relative to the configured target
+	// directory, including any sub-directories.
+	RelPath string
+	// Client, if set, must be used to fetch URL instead of a bare
+	// default client. Sources whose downloads depend on state gathered
+	// during enumeration (e.g. bookget's session cookie) set this to a
+	// client that carries that state.
+	Client *http.Client
+}
+
+// Params describes one configured page. It is shared by every Source
+// implementation, which is free to ignore the fields it doesn't need.
+type Params struct {
+	Name       string   `yaml:"name"`
+	Url        string   `yaml:"url"`
+	Type       string   `yaml:"type"`
+	PageRanges []string `yaml:"pageRanges"`
+	TileWidth  int      `yaml:"tileWidth"`
+	TileHeight int      `yaml:"tileHeight"`
+	Manifest   string   `yaml:"manifest"`
+	// Stitch requests that downloaded tiles be reassembled into a mosaic
+	// afterwards. Only supported for source types whose on-disk naming
+	// stitch.Dir knows how to parse back into a tile grid - see
+	// StitchSupported.
+	Stitch        bool   `yaml:"stitch"`
+	COG           bool   `yaml:"cog"`
+	LinkSelector  string `yaml:"linkSelector"`
+	AssetSelector string `yaml:"assetSelector"`
+	URLPattern    string `yaml:"urlPattern"`
+}
+
+// Source enumerates the assets behind one configured page.
+type Source interface {
+	// Enumerate discovers assets and streams them on the returned
+	// channel. The channel is closed once enumeration finishes or ctx is
+	// done.
+	Enumerate(ctx context.Context) (<-chan Asset, error)
+}
+
+// Factory builds a Source from page parameters.
+type Factory func(params Params) (Source, error)
+
+var registry = map[string]Factory{}
+
+// Register adds a Source implementation under name, so it can be selected
+// via Params.Type. It is meant to be called from each backend's init().
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// defaultType is used when a page doesn't declare a type, preserving the
+// tool's original ISRIC-only behaviour.
+const defaultType = "isric"
+
+// stitchableTypes are the source types whose downloaded filenames stitch.Dir
+// knows how to parse back into a tile grid (currently just ISRIC's
+// tileSG-<row><col> naming).
+var stitchableTypes = map[string]bool{
+	defaultType: true,
+}
+
+// StitchSupported reports whether assets downloaded from a source of typ can
+// be reassembled by stitch.Dir. An empty typ means defaultType.
+func StitchSupported(typ string) bool {
+	if typ == "" {
+		typ = defaultType
+	}
+	return stitchableTypes[typ]
+}
+
+// New builds the Source registered for params.Type.
+func New(params Params) (Source, error) {
+	typ := params.Type
+	if typ == "" {
+		typ = defaultType
+	}
+
+	factory, ok := registry[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown source type %q", typ)
+	}
+	return factory(params)
+}
@@ -0,0 +1,21 @@
+package source
+
+import "testing"
+
+func TestStitchSupported(t *testing.T) {
+	cases := []struct {
+		typ  string
+		want bool
+	}{
+		{"", true},
+		{"isric", true},
+		{"iiif", false},
+		{"directory-listing", false},
+		{"bookget", false},
+	}
+	for _, c := range cases {
+		if got := StitchSupported(c.typ); got != c.want {
+			t.Errorf("StitchSupported(%q) = %v, want %v", c.typ, got, c.want)
+		}
+	}
+}
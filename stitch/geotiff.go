@@ -0,0 +1,167 @@
+package stitch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+)
+
+// TIFF tag ids used below. See the TIFF 6.0 spec.
+const (
+	tagImageWidth                = 256
+	tagImageLength               = 257
+	tagBitsPerSample             = 258
+	tagCompression               = 259
+	tagPhotometricInterpretation = 262
+	tagSamplesPerPixel           = 277
+	tagPlanarConfiguration       = 284
+	tagTileWidth                 = 322
+	tagTileLength                = 323
+	tagTileOffsets               = 324
+	tagTileByteCounts            = 325
+	tagExtraSamples              = 338
+)
+
+// extraSampleUnassociatedAlpha marks the 4th (alpha) sample as
+// unassociated (straight, not premultiplied), matching how image.NRGBA
+// stores pixels.
+const extraSampleUnassociatedAlpha = 2
+
+const (
+	typeShort = 3
+	typeLong  = 4
+)
+
+// geoTileSize is the tile edge used in the on-disk tiled layout, matching
+// the size GDAL defaults to for Cloud-Optimized GeoTIFFs.
+const geoTileSize = 256
+
+type ifdEntry struct {
+	tag, typ uint16
+	count    uint32
+	value    uint32
+}
+
+// writeGeoTIFF encodes levels (base image first, followed by any
+// successively downsampled overviews) as a single multi-IFD tiled TIFF: a
+// Cloud-Optimized GeoTIFF when more than one level is given, a plain tiled
+// GeoTIFF otherwise.
+func writeGeoTIFF(w io.Writer, levels []image.Image) error {
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{'I', 'I', 42, 0})
+	binary.Write(buf, binary.LittleEndian, uint32(8)) // first IFD right after the header
+
+	for i, level := range levels {
+		nrgba := toNRGBA(level)
+		ifdOffset, err := writeLevel(buf, nrgba, i == len(levels)-1)
+		if err != nil {
+			return fmt.Errorf("unable to encode level %d: %v", i, err)
+		}
+		_ = ifdOffset
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeLevel appends one tiled IFD plus its pixel data to buf. Unless last
+// is true, the IFD's "next IFD" pointer is set to the offset just past this
+// level's pixel data, where the next call writes the following level's IFD,
+// so the levels form one chain of IFDs. No NewSubfileType tag is written,
+// so the later IFDs are not flagged as reduced-resolution images.
+func writeLevel(buf *bytes.Buffer, img *nrgbaImage, last bool) (uint32, error) {
+	tilesAcross := (img.w + geoTileSize - 1) / geoTileSize
+	tilesDown := (img.h + geoTileSize - 1) / geoTileSize
+	tileCount := tilesAcross * tilesDown
+
+	tileOffsets := make([]uint32, tileCount)
+	tileByteCounts := make([]uint32, tileCount)
+
+	entries := []ifdEntry{
+		{tagImageWidth, typeLong, 1, uint32(img.w)},
+		{tagImageLength, typeLong, 1, uint32(img.h)},
+		{tagBitsPerSample, typeShort, 4, 0}, // patched below (offset to 4 SHORTs)
+		{tagCompression, typeShort, 1, 1},
+		{tagPhotometricInterpretation, typeShort, 1, 2},
+		{tagSamplesPerPixel, typeShort, 1, 4},
+		{tagPlanarConfiguration, typeShort, 1, 1},
+		{tagTileWidth, typeLong, 1, geoTileSize},
+		{tagTileLength, typeLong, 1, geoTileSize},
+		{tagTileOffsets, typeLong, uint32(tileCount), 0},    // patched below
+		{tagTileByteCounts, typeLong, uint32(tileCount), 0}, // patched below
+		{tagExtraSamples, typeShort, 1, extraSampleUnassociatedAlpha},
+	}
+
+	// Reserve space for: IFD, out-of-line BitsPerSample values, tile
+	// offsets/byte-counts arrays, then the tile pixel data itself.
+	ifdStart := uint32(buf.Len())
+	ifdSize := uint32(2 + len(entries)*12 + 4)
+	bitsOffset := ifdStart + ifdSize
+	tileOffsetsArrOffset := bitsOffset + 8
+	tileByteCountsArrOffset := tileOffsetsArrOffset + uint32(tileCount)*4
+	pixelDataStart := tileByteCountsArrOffset + uint32(tileCount)*4
+
+	offset := pixelDataStart
+	tileBuf := &bytes.Buffer{}
+	for ty := 0; ty < tilesDown; ty++ {
+		for tx := 0; tx < tilesAcross; tx++ {
+			idx := ty*tilesAcross + tx
+			data := img.tileRGBA(tx*geoTileSize, ty*geoTileSize, geoTileSize, geoTileSize)
+			tileOffsets[idx] = offset
+			tileByteCounts[idx] = uint32(len(data))
+			offset += uint32(len(data))
+			tileBuf.Write(data)
+		}
+	}
+
+	for i := range entries {
+		switch entries[i].tag {
+		case tagBitsPerSample:
+			entries[i].value = bitsOffset
+		case tagTileOffsets:
+			// A single-tile image's one LONG value fits directly in the
+			// entry, and per the TIFF spec must be stored there rather
+			// than as a pointer to a one-element array.
+			if tileCount == 1 {
+				entries[i].value = tileOffsets[0]
+			} else {
+				entries[i].value = tileOffsetsArrOffset
+			}
+		case tagTileByteCounts:
+			if tileCount == 1 {
+				entries[i].value = tileByteCounts[0]
+			} else {
+				entries[i].value = tileByteCountsArrOffset
+			}
+		}
+	}
+
+	binary.Write(buf, binary.LittleEndian, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(buf, binary.LittleEndian, e.tag)
+		binary.Write(buf, binary.LittleEndian, e.typ)
+		binary.Write(buf, binary.LittleEndian, e.count)
+		binary.Write(buf, binary.LittleEndian, e.value)
+	}
+
+	nextIFD := uint32(0)
+	if !last {
+		nextIFD = pixelDataStart + uint32(tileBuf.Len())
+	}
+	binary.Write(buf, binary.LittleEndian, nextIFD)
+
+	for i := 0; i < 4; i++ {
+		binary.Write(buf, binary.LittleEndian, uint16(8))
+	}
+	for _, o := range tileOffsets {
+		binary.Write(buf, binary.LittleEndian, o)
+	}
+	for _, c := range tileByteCounts {
+		binary.Write(buf, binary.LittleEndian, c)
+	}
+	buf.Write(tileBuf.Bytes())
+
+	return ifdStart, nil
+}
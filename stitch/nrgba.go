@@ -0,0 +1,49 @@
+package stitch
+
+import "image"
+
+// nrgbaImage is a thin wrapper letting writeGeoTIFF slice out raw tile
+// pixel data without re-deriving bounds math for every sub-rectangle.
+type nrgbaImage struct {
+	*image.NRGBA
+	w, h int
+}
+
+func toNRGBA(img image.Image) *nrgbaImage {
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		b := img.Bounds()
+		nrgba = image.NewNRGBA(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				nrgba.Set(x, y, img.At(x, y))
+			}
+		}
+	}
+	b := nrgba.Bounds()
+	return &nrgbaImage{NRGBA: nrgba, w: b.Dx(), h: b.Dy()}
+}
+
+// tileRGBA returns the raw, row-major RGBA bytes for the w x h region at
+// (x0, y0), zero-padding any part that falls outside the image so every
+// tile in the TIFF is a uniform size as the spec requires.
+func (img *nrgbaImage) tileRGBA(x0, y0, w, h int) []byte {
+	out := make([]byte, w*h*4)
+	b := img.NRGBA.Bounds()
+	for y := 0; y < h; y++ {
+		sy := b.Min.Y + y0 + y
+		if sy >= b.Max.Y {
+			continue
+		}
+		for x := 0; x < w; x++ {
+			sx := b.Min.X + x0 + x
+			if sx >= b.Max.X {
+				continue
+			}
+			c := img.NRGBA.NRGBAAt(sx, sy)
+			i := (y*w + x) * 4
+			out[i], out[i+1], out[i+2], out[i+3] = c.R, c.G, c.B, c.A
+		}
+	}
+	return out
+}
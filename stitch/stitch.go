@@ -0,0 +1,161 @@
+// Package stitch reassembles the individual tiles downloaded for a page
+// into a single mosaic image, optionally written out as a tiled,
+// multi-resolution (Cloud-Optimized) GeoTIFF.
+package stitch
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/image/tiff"
+
+	"github.com/raymanovg/isric/source"
+)
+
+// tile is one decoded tileSG-<row><col>.tif file, positioned by its grid
+// coordinates rather than by the order it was read from disk.
+type tile struct {
+	row, col int
+	img      image.Image
+}
+
+// Dir stitches every tileSG-<row><col>.tif file directly inside dir into a
+// single mosaic and writes it to outPath. When cog is true the output also
+// carries successive 2x-downsampled overview levels.
+func Dir(dir, outPath string, cog bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("unable to read tile dir %s: %v", dir, err)
+	}
+
+	var tiles []tile
+	maxRow, maxCol := 0, 0
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		row, col, ok := source.ParseTileCoord(entry.Name())
+		if !ok {
+			continue
+		}
+
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("unable to open tile %s: %v", entry.Name(), err)
+		}
+		img, err := tiff.Decode(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("unable to decode tile %s: %v", entry.Name(), err)
+		}
+
+		if row > maxRow {
+			maxRow = row
+		}
+		if col > maxCol {
+			maxCol = col
+		}
+		tiles = append(tiles, tile{row: row, col: col, img: img})
+	}
+
+	if len(tiles) == 0 {
+		return fmt.Errorf("no tiles found in %s", dir)
+	}
+
+	tileW, tileH := gridTileSize(tiles)
+	mosaic := image.NewNRGBA(image.Rect(0, 0, (maxCol+1)*tileW, (maxRow+1)*tileH))
+	for _, t := range tiles {
+		origin := image.Pt(t.col*tileW, t.row*tileH)
+		draw.Draw(mosaic, image.Rectangle{Min: origin, Max: origin.Add(t.img.Bounds().Size())}, t.img, t.img.Bounds().Min, draw.Src)
+	}
+
+	levels := []image.Image{mosaic}
+	if cog {
+		levels = append(levels, overviews(mosaic)...)
+	}
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("unable to create mosaic file %s: %v", outPath, err)
+	}
+	defer out.Close()
+
+	return writeGeoTIFF(out, levels)
+}
+
+// gridTileSize picks the tile dimensions used to place every tile on the
+// mosaic canvas. It prefers the row 0, col 0 tile, since that's the one
+// whose origin math (0, 0) is trusted regardless of tile size, falling
+// back to the largest tile seen if that corner is missing - os.ReadDir
+// order is not a reliable source since a partial/edge tile can sort
+// first and would otherwise mis-place and mis-size the whole mosaic.
+func gridTileSize(tiles []tile) (w, h int) {
+	for _, t := range tiles {
+		if t.row == 0 && t.col == 0 {
+			b := t.img.Bounds()
+			return b.Dx(), b.Dy()
+		}
+	}
+
+	for _, t := range tiles {
+		b := t.img.Bounds()
+		if b.Dx() > w {
+			w = b.Dx()
+		}
+		if b.Dy() > h {
+			h = b.Dy()
+		}
+	}
+	return w, h
+}
+
+// overviews builds successive 2x box-filter downsamples of base until
+// either dimension drops below a tile, as GDAL's -co TILED=YES -co
+// OVERVIEWS would for a Cloud-Optimized GeoTIFF.
+func overviews(base *image.NRGBA) []image.Image {
+	const minDim = 256
+
+	var levels []image.Image
+	current := base
+	for current.Bounds().Dx() > minDim && current.Bounds().Dy() > minDim {
+		current = downsample2x(current)
+		levels = append(levels, current)
+	}
+	return levels
+}
+
+func downsample2x(src *image.NRGBA) *image.NRGBA {
+	sb := src.Bounds()
+	dw, dh := (sb.Dx()+1)/2, (sb.Dy()+1)/2
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var r, g, b, a, n uint32
+			for _, dy := range [2]int{0, 1} {
+				for _, dx := range [2]int{0, 1} {
+					sx, sy := sb.Min.X+x*2+dx, sb.Min.Y+y*2+dy
+					if sx >= sb.Max.X || sy >= sb.Max.Y {
+						continue
+					}
+					c := src.NRGBAAt(sx, sy)
+					r += uint32(c.R)
+					g += uint32(c.G)
+					b += uint32(c.B)
+					a += uint32(c.A)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,114 @@
+package stitch
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+// solidNRGBA returns a w x h image filled with c, so tests can tell tiles
+// apart by sampling a single pixel.
+func solidNRGBA(w, h int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func writeTile(t *testing.T, dir, name string, img image.Image) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("create tile %s: %v", name, err)
+	}
+	defer f.Close()
+	if err := tiff.Encode(f, img, nil); err != nil {
+		t.Fatalf("encode tile %s: %v", name, err)
+	}
+}
+
+func TestWriteGeoTIFFRoundTrips(t *testing.T) {
+	base := solidNRGBA(300, 200, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+	overview := downsample2x(base)
+
+	out, err := os.CreateTemp(t.TempDir(), "mosaic-*.tif")
+	if err != nil {
+		t.Fatalf("create temp file: %v", err)
+	}
+	defer out.Close()
+
+	if err := writeGeoTIFF(out, []image.Image{base, overview}); err != nil {
+		t.Fatalf("writeGeoTIFF: %v", err)
+	}
+
+	if _, err := out.Seek(0, 0); err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	decoded, err := tiff.Decode(out)
+	if err != nil {
+		t.Fatalf("tiff.Decode: %v", err)
+	}
+
+	gotBounds := decoded.Bounds()
+	if gotBounds.Dx() != base.Bounds().Dx() || gotBounds.Dy() != base.Bounds().Dy() {
+		t.Fatalf("decoded bounds = %v, want %v", gotBounds, base.Bounds())
+	}
+
+	for _, p := range []image.Point{{0, 0}, {299, 199}, {150, 100}} {
+		want := base.NRGBAAt(p.X, p.Y)
+		got := decoded.At(p.X, p.Y)
+		r, g, b, a := got.RGBA()
+		if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B || uint8(a>>8) != want.A {
+			t.Errorf("pixel %v = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestDirDerivesTileSizeFromOrigin(t *testing.T) {
+	dir := t.TempDir()
+
+	// row 0 tiles are full-size; the row 1 tiles are a short edge row, as
+	// ISRIC's real rasters produce when the image doesn't divide evenly
+	// into tiles. An edge tile is written first, but os.ReadDir returns
+	// entries sorted by name, so tileSG-0000.tif is still read first.
+	writeTile(t, dir, "tileSG-0100.tif", solidNRGBA(64, 32, color.NRGBA{R: 1, A: 255}))
+	writeTile(t, dir, "tileSG-0000.tif", solidNRGBA(64, 64, color.NRGBA{R: 2, A: 255}))
+	writeTile(t, dir, "tileSG-0001.tif", solidNRGBA(64, 64, color.NRGBA{R: 3, A: 255}))
+	writeTile(t, dir, "tileSG-0101.tif", solidNRGBA(64, 32, color.NRGBA{R: 4, A: 255}))
+
+	outPath := filepath.Join(dir, "mosaic.tif")
+	if err := Dir(dir, outPath, false); err != nil {
+		t.Fatalf("Dir: %v", err)
+	}
+
+	f, err := os.Open(outPath)
+	if err != nil {
+		t.Fatalf("open mosaic: %v", err)
+	}
+	defer f.Close()
+	mosaic, err := tiff.Decode(f)
+	if err != nil {
+		t.Fatalf("decode mosaic: %v", err)
+	}
+
+	// Tile size must come from the (0,0) tile (64x64), not a short 64x32
+	// edge tile, so the canvas is 128x128, not 128x64.
+	wantBounds := image.Rect(0, 0, 128, 128)
+	if mosaic.Bounds() != wantBounds {
+		t.Fatalf("mosaic bounds = %v, want %v", mosaic.Bounds(), wantBounds)
+	}
+
+	// The (1,0) edge tile is short (32px tall); it must still be placed at
+	// row*tileH = 64, not squeezed against row 0.
+	r, _, _, _ := mosaic.At(10, 64).RGBA()
+	if uint8(r>>8) != 1 {
+		t.Errorf("pixel in row-1 tile = %d, want 1", uint8(r>>8))
+	}
+}